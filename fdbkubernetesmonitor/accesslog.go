@@ -0,0 +1,82 @@
+// accesslog.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// newAccessLogger creates a zap.Logger that writes one JSON line per HTTP
+// request to a rotating log file.
+func newAccessLogger(accessLogFile string, maxSize int, maxBackups int) *zap.Logger {
+	lumberjackLogger := &lumberjack.Logger{
+		Filename:   accessLogFile,
+		MaxSize:    maxSize,
+		MaxBackups: maxBackups,
+	}
+
+	return zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(lumberjackLogger), zapcore.InfoLevel))
+}
+
+// statusRecorder wraps a http.ResponseWriter so the access log middleware
+// can observe the status code and number of bytes written in the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (recorder *statusRecorder) WriteHeader(status int) {
+	recorder.status = status
+	recorder.ResponseWriter.WriteHeader(status)
+}
+
+func (recorder *statusRecorder) Write(data []byte) (int, error) {
+	written, err := recorder.ResponseWriter.Write(data)
+	recorder.bytes += written
+	return written, err
+}
+
+// accessLogMiddleware wraps an http.Handler, logging one entry per request
+// to accessLogger describing the request method, path, status, size,
+// latency, remote address, and user agent.
+func accessLogMiddleware(accessLogger *zap.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+
+		next.ServeHTTP(recorder, request)
+
+		accessLogger.Info("Handled HTTP request",
+			zap.String("method", request.Method),
+			zap.String("path", request.URL.Path),
+			zap.Int("status", recorder.status),
+			zap.Int("bytes", recorder.bytes),
+			zap.String("remoteAddr", request.RemoteAddr),
+			zap.String("userAgent", request.UserAgent()),
+			zap.Duration("latency", time.Since(start)),
+		)
+	})
+}
@@ -0,0 +1,105 @@
+// accesslog_test.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestAccessLogMiddlewareRecordsRequestDetails(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	accessLogger := zap.New(core)
+
+	handler := accessLogMiddleware(accessLogger, http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusTeapot)
+		_, _ = writer.Write([]byte("hello"))
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/status", nil)
+	request.RemoteAddr = "10.0.0.1:12345"
+	request.Header.Set("User-Agent", "test-agent")
+
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly one access log entry, got %d", len(entries))
+	}
+
+	fields := entries[0].ContextMap()
+	if fields["method"] != http.MethodGet {
+		t.Errorf("expected method %q, got %q", http.MethodGet, fields["method"])
+	}
+	if fields["path"] != "/status" {
+		t.Errorf("expected path %q, got %q", "/status", fields["path"])
+	}
+	if fields["status"] != int64(http.StatusTeapot) {
+		t.Errorf("expected status %d, got %v", http.StatusTeapot, fields["status"])
+	}
+	if fields["bytes"] != int64(len("hello")) {
+		t.Errorf("expected bytes %d, got %v", len("hello"), fields["bytes"])
+	}
+	if fields["remoteAddr"] != "10.0.0.1:12345" {
+		t.Errorf("expected remoteAddr %q, got %q", "10.0.0.1:12345", fields["remoteAddr"])
+	}
+	if fields["userAgent"] != "test-agent" {
+		t.Errorf("expected userAgent %q, got %q", "test-agent", fields["userAgent"])
+	}
+	if _, present := fields["latency"]; !present {
+		t.Error("expected a latency field to be recorded")
+	}
+}
+
+func TestAccessLogMiddlewareDefaultsStatusToOK(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	accessLogger := zap.New(core)
+
+	handler := accessLogMiddleware(accessLogger, http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		_, _ = writer.Write([]byte("ok"))
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected exactly one access log entry, got %d", len(entries))
+	}
+	if status := entries[0].ContextMap()["status"]; status != int64(http.StatusOK) {
+		t.Errorf("expected a handler that never calls WriteHeader to default to status 200, got %v", status)
+	}
+}
+
+func TestNewAccessLoggerWritesToRotatingFile(t *testing.T) {
+	logFile := t.TempDir() + "/access.log"
+
+	accessLogger := newAccessLogger(logFile, 1, 1)
+	accessLogger.Info("test entry")
+	_ = accessLogger.Sync()
+
+	if _, err := os.Stat(logFile); err != nil {
+		t.Fatalf("expected newAccessLogger to create %s: %s", logFile, err)
+	}
+}
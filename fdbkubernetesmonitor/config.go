@@ -0,0 +1,210 @@
+// config.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/go-logr/logr"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// launcherConfig is the on-disk representation of the --config file. It
+// mirrors the launcher's command-line flags so that a single file can
+// supply everything the launcher needs, and so that in launcher/sidecar
+// modes it can be watched for changes.
+type launcherConfig struct {
+	CopyFiles             []string          `json:"copyFiles,omitempty" yaml:"copyFiles,omitempty"`
+	CopyBinaries          []string          `json:"copyBinaries,omitempty" yaml:"copyBinaries,omitempty"`
+	CopyLibraries         []string          `json:"copyLibraries,omitempty" yaml:"copyLibraries,omitempty"`
+	CopyPrimaryLibrary    string            `json:"copyPrimaryLibrary,omitempty" yaml:"copyPrimaryLibrary,omitempty"`
+	RequiredCopyFiles     []string          `json:"requiredCopyFiles,omitempty" yaml:"requiredCopyFiles,omitempty"`
+	CopyTrees             []string          `json:"copyTrees,omitempty" yaml:"copyTrees,omitempty"`
+	CopyIncludeGlobs      []string          `json:"copyIncludeGlobs,omitempty" yaml:"copyIncludeGlobs,omitempty"`
+	CopyExcludeGlobs      []string          `json:"copyExcludeGlobs,omitempty" yaml:"copyExcludeGlobs,omitempty"`
+	ProcessCount          int               `json:"processCount,omitempty" yaml:"processCount,omitempty"`
+	AdditionalEnvironment map[string]string `json:"additionalEnvironment,omitempty" yaml:"additionalEnvironment,omitempty"`
+	LogLevel              string            `json:"logLevel,omitempty" yaml:"logLevel,omitempty"`
+	LogFormat             string            `json:"logFormat,omitempty" yaml:"logFormat,omitempty"`
+}
+
+// loadLauncherConfig reads and parses the --config file, auto-detecting the
+// format (YAML or JSON) from its extension.
+func loadLauncherConfig(path string) (*launcherConfig, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &launcherConfig{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(contents, config)
+	case ".json":
+		err = json.Unmarshal(contents, config)
+	default:
+		return nil, fmt.Errorf("unrecognized --config file extension %q, expected .yaml or .json", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// applyLauncherConfig copies the settings in config into the launcher's
+// package-level flag variables, skipping any flag that was explicitly set
+// on the command line so that flags always take precedence over the config
+// file.
+func applyLauncherConfig(config *launcherConfig) {
+	setByFlag := func(name string) bool {
+		flag := pflag.CommandLine.Lookup(name)
+		return flag != nil && flag.Changed
+	}
+
+	if len(config.CopyFiles) > 0 && !setByFlag("copy-file") {
+		copyFiles = config.CopyFiles
+	}
+	if len(config.CopyBinaries) > 0 && !setByFlag("copy-binary") {
+		copyBinaries = config.CopyBinaries
+	}
+	if len(config.CopyLibraries) > 0 && !setByFlag("copy-library") {
+		copyLibraries = config.CopyLibraries
+	}
+	if config.CopyPrimaryLibrary != "" && !setByFlag("copy-primary-library") {
+		copyPrimaryLibrary = config.CopyPrimaryLibrary
+	}
+	if len(config.RequiredCopyFiles) > 0 && !setByFlag("require-not-empty") {
+		requiredCopyFiles = config.RequiredCopyFiles
+	}
+	if len(config.CopyTrees) > 0 && !setByFlag("copy-tree") {
+		copyTrees = config.CopyTrees
+	}
+	if len(config.CopyIncludeGlobs) > 0 && !setByFlag("copy-include") {
+		copyIncludeGlobs = config.CopyIncludeGlobs
+	}
+	if len(config.CopyExcludeGlobs) > 0 && !setByFlag("copy-exclude") {
+		copyExcludeGlobs = config.CopyExcludeGlobs
+	}
+	if config.ProcessCount > 0 && !setByFlag("process-count") {
+		processCount = config.ProcessCount
+	}
+	if config.LogLevel != "" && !setByFlag("log-level") {
+		logLevel = config.LogLevel
+	}
+	if config.LogFormat != "" && !setByFlag("log-format") {
+		logFormat = config.LogFormat
+	}
+}
+
+// mergeEnvironments combines baseEnvironment (typically loaded from
+// --additional-env-file) with overlay (typically a --config file's
+// additionalEnvironment), with overlay taking precedence on conflicts.
+// Either map may be nil.
+func mergeEnvironments(baseEnvironment map[string]string, overlay map[string]string) map[string]string {
+	merged := make(map[string]string, len(baseEnvironment)+len(overlay))
+	for key, value := range baseEnvironment {
+		merged[key] = value
+	}
+	for key, value := range overlay {
+		merged[key] = value
+	}
+	return merged
+}
+
+// watchLauncherConfig watches configFile for changes and, on every write,
+// re-evaluates the copy list and log settings and, if configUpdates is
+// non-nil, sends the updated process count and environment to it so a
+// running monitor can adopt them without the pod being restarted. If
+// logCore is non-nil, it is rebuilt in place so a changed logLevel or
+// logFormat takes effect immediately.
+func watchLauncherConfig(logger logr.Logger, configFile string, baseEnvironment map[string]string, configUpdates chan<- ConfigUpdate, logCore *swappableCore) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logger.Error(err, "Error creating config file watcher")
+		return
+	}
+	defer watcher.Close()
+
+	err = watcher.Add(filepath.Dir(configFile))
+	if err != nil {
+		logger.Error(err, "Error watching config file directory", "configFile", configFile)
+		return
+	}
+
+	for event := range watcher.Events {
+		if filepath.Clean(event.Name) != filepath.Clean(configFile) {
+			continue
+		}
+		if event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+			continue
+		}
+
+		logger.Info("Config file changed, reloading", "configFile", configFile)
+
+		config, err := loadLauncherConfig(configFile)
+		if err != nil {
+			logger.Error(err, "Error reloading config file", "configFile", configFile)
+			continue
+		}
+
+		applyLauncherConfig(config)
+
+		if logCore != nil {
+			logCore.rebuild(buildLoggerCore(logPath))
+		}
+
+		copyDetails, requiredCopies, err := getCopyDetails()
+		if err != nil {
+			logger.Error(err, "Error getting list of files to copy after config reload")
+			continue
+		}
+
+		copyTreeDetails, err := getCopyTrees()
+		if err != nil {
+			logger.Error(err, "Error parsing --copy-tree entries after config reload")
+			continue
+		}
+
+		if executionMode(executionModeString) != executionModeSidecar || mainContainerVersion != currentContainerVersion {
+			err = CopyFiles(logger, outputDir, copyDetails, requiredCopies, copyTreeDetails, copyIncludeGlobs, copyExcludeGlobs)
+		}
+		if err != nil {
+			logger.Error(err, "Error copying files after config reload")
+			continue
+		}
+
+		mergedEnvironment := mergeEnvironments(baseEnvironment, config.AdditionalEnvironment)
+
+		if configUpdates != nil {
+			configUpdates <- ConfigUpdate{
+				ProcessCount:      processCount,
+				CustomEnvironment: mergedEnvironment,
+			}
+		}
+	}
+}
@@ -0,0 +1,96 @@
+// config_test.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// registerTestFlag registers name on pflag.CommandLine if it is not already
+// registered, so tests can exercise applyLauncherConfig's flag-precedence
+// checks without depending on main() having run first.
+func registerTestFlag(name string, value *string, defaultValue string) {
+	if pflag.CommandLine.Lookup(name) == nil {
+		pflag.StringVar(value, name, defaultValue, "test flag")
+	}
+}
+
+func TestApplyLauncherConfigSkipsValuesSetByFlag(t *testing.T) {
+	var logLevelFlagValue string
+	registerTestFlag("log-level", &logLevelFlagValue, "info")
+	if err := pflag.CommandLine.Set("log-level", "debug"); err != nil {
+		t.Fatalf("failed to set log-level flag: %s", err)
+	}
+
+	originalLogLevel := logLevel
+	defer func() { logLevel = originalLogLevel }()
+	logLevel = "debug"
+
+	applyLauncherConfig(&launcherConfig{LogLevel: "error"})
+
+	if logLevel != "debug" {
+		t.Fatalf("expected a flag-set value to take precedence over the config file, got %q", logLevel)
+	}
+}
+
+func TestApplyLauncherConfigAppliesValuesNotSetByFlag(t *testing.T) {
+	var processCountFlagValue string
+	registerTestFlag("process-count", &processCountFlagValue, "")
+
+	originalProcessCount := processCount
+	defer func() { processCount = originalProcessCount }()
+	processCount = 1
+
+	applyLauncherConfig(&launcherConfig{ProcessCount: 3})
+
+	if processCount != 3 {
+		t.Fatalf("expected the config file value to apply when no flag was set, got %d", processCount)
+	}
+}
+
+func TestMergeEnvironmentsOverlayTakesPrecedence(t *testing.T) {
+	base := map[string]string{"A": "base-a", "B": "base-b"}
+	overlay := map[string]string{"B": "overlay-b", "C": "overlay-c"}
+
+	merged := mergeEnvironments(base, overlay)
+
+	expected := map[string]string{"A": "base-a", "B": "overlay-b", "C": "overlay-c"}
+	for key, value := range expected {
+		if merged[key] != value {
+			t.Errorf("expected merged[%q] = %q, got %q", key, value, merged[key])
+		}
+	}
+	if len(merged) != len(expected) {
+		t.Errorf("expected %d merged keys, got %d: %v", len(expected), len(merged), merged)
+	}
+}
+
+func TestMergeEnvironmentsHandlesNilMaps(t *testing.T) {
+	if merged := mergeEnvironments(nil, nil); len(merged) != 0 {
+		t.Fatalf("expected merging two nil maps to produce an empty map, got %v", merged)
+	}
+
+	overlay := map[string]string{"A": "a"}
+	if merged := mergeEnvironments(nil, overlay); merged["A"] != "a" {
+		t.Fatalf("expected a nil base to be ignored, got %v", merged)
+	}
+}
@@ -0,0 +1,221 @@
+// copy_files.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-logr/logr"
+)
+
+// treeCopy describes a single --copy-tree entry: a source directory to walk
+// recursively and the destination directory, relative to the output
+// directory, to copy matching files into.
+type treeCopy struct {
+	src string
+	dst string
+}
+
+// CopyFiles copies the files described by copyDetails into outputDir, and
+// recursively copies the directory trees described by copyTrees, filtered
+// by includeGlobs and excludeGlobs. Every pattern in requiredNotEmpty must
+// match at least one copied file, and every file it matches must be
+// non-empty, or CopyFiles returns an error.
+func CopyFiles(logger logr.Logger, outputDir string, copyDetails map[string]string, requiredNotEmpty map[string]bool, copyTrees []treeCopy, includeGlobs []string, excludeGlobs []string) error {
+	matchedRequired := make(map[string]bool, len(requiredNotEmpty))
+
+	for srcFile, destRelativePath := range copyDetails {
+		destFile := destRelativePath
+		if destFile == "" {
+			destFile = filepath.Base(srcFile)
+		}
+
+		err := copyPath(logger, srcFile, filepath.Join(outputDir, destFile))
+		if err != nil {
+			return err
+		}
+
+		err = checkRequiredNotEmpty(srcFile, srcFile, requiredNotEmpty, matchedRequired)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, tree := range copyTrees {
+		err := filepath.Walk(tree.src, func(currentPath string, info os.FileInfo, walkErr error) error {
+			if walkErr != nil {
+				return walkErr
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			relativePath, err := filepath.Rel(tree.src, currentPath)
+			if err != nil {
+				return err
+			}
+
+			included, err := matchesGlobs(relativePath, includeGlobs, excludeGlobs)
+			if err != nil {
+				return err
+			}
+			if !included {
+				return nil
+			}
+
+			logger.Info("Copying file from tree", "src", currentPath, "tree", tree.src, "dst", tree.dst)
+			err = copyPath(logger, currentPath, filepath.Join(outputDir, tree.dst, relativePath))
+			if err != nil {
+				return err
+			}
+
+			return checkRequiredNotEmpty(relativePath, currentPath, requiredNotEmpty, matchedRequired)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for pattern := range requiredNotEmpty {
+		if !matchedRequired[pattern] {
+			return fmt.Errorf("file %s is required, but did not match any copied file", pattern)
+		}
+	}
+
+	return nil
+}
+
+// matchesGlobs returns true if relativePath matches at least one pattern in
+// includeGlobs (or includeGlobs is empty) and does not match any pattern in
+// excludeGlobs.
+func matchesGlobs(relativePath string, includeGlobs []string, excludeGlobs []string) (bool, error) {
+	for _, pattern := range excludeGlobs {
+		matched, err := filepath.Match(pattern, relativePath)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return false, nil
+		}
+	}
+
+	if len(includeGlobs) == 0 {
+		return true, nil
+	}
+
+	for _, pattern := range includeGlobs {
+		matched, err := filepath.Match(pattern, relativePath)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkRequiredNotEmpty marks every pattern in requiredNotEmpty that matches
+// candidate as satisfied, and returns an error if srcFile is empty despite
+// matching one of those patterns.
+func checkRequiredNotEmpty(candidate string, srcFile string, requiredNotEmpty map[string]bool, matchedRequired map[string]bool) error {
+	for pattern := range requiredNotEmpty {
+		matched := pattern == candidate
+		if !matched {
+			matched, _ = filepath.Match(pattern, candidate)
+		}
+		if !matched {
+			continue
+		}
+
+		matchedRequired[pattern] = true
+
+		info, err := os.Stat(srcFile)
+		if err != nil {
+			return err
+		}
+		if info.Size() == 0 {
+			return fmt.Errorf("file %s is required to be non-empty, but is empty", srcFile)
+		}
+	}
+
+	return nil
+}
+
+// copyPath copies a single file from src to dest, creating any necessary
+// parent directories and preserving the source file's mode bits and, where
+// possible, its ownership. Symlinks are recreated rather than followed.
+func copyPath(logger logr.Logger, src string, dest string) error {
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		_ = os.Remove(dest)
+		err = os.Symlink(target, dest)
+		if err != nil {
+			return err
+		}
+		return copyOwnership(src, dest)
+	}
+
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer sourceFile.Close()
+
+	destFile, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer destFile.Close()
+
+	_, err = io.Copy(destFile, sourceFile)
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(dest, info.Mode())
+	if err != nil {
+		return err
+	}
+
+	err = copyOwnership(src, dest)
+	if err != nil {
+		return err
+	}
+
+	return copyXattrs(src, dest)
+}
@@ -0,0 +1,92 @@
+// copy_files_linux.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build linux
+
+package main
+
+import "golang.org/x/sys/unix"
+
+// copyXattrs copies the extended attributes set on src onto dest. This is
+// only supported on Linux; other platforms use a no-op implementation in
+// copy_files_other.go.
+func copyXattrs(src string, dest string) error {
+	names, err := unix.Llistxattr(src, nil)
+	if err != nil {
+		// Not all filesystems support extended attributes; treat that as
+		// nothing to copy rather than a hard failure.
+		return nil
+	}
+
+	namesBuffer := make([]byte, names)
+	size, err := unix.Llistxattr(src, namesBuffer)
+	if err != nil {
+		return nil
+	}
+
+	for _, name := range splitXattrNames(namesBuffer[:size]) {
+		valueSize, err := unix.Lgetxattr(src, name, nil)
+		if err != nil {
+			continue
+		}
+
+		value := make([]byte, valueSize)
+		_, err = unix.Lgetxattr(src, name, value)
+		if err != nil {
+			continue
+		}
+
+		_ = unix.Lsetxattr(dest, name, value, 0)
+	}
+
+	return nil
+}
+
+// splitXattrNames splits the NUL-separated attribute name list returned by
+// listxattr into individual names.
+func splitXattrNames(namesBuffer []byte) []string {
+	var names []string
+	start := 0
+	for i, b := range namesBuffer {
+		if b == 0 {
+			if i > start {
+				names = append(names, string(namesBuffer[start:i]))
+			}
+			start = i + 1
+		}
+	}
+	return names
+}
+
+// copyOwnership sets dest's owner and group to match src. This is
+// best-effort: the copying process commonly does not have permission to
+// change ownership (e.g. it is not running as root), and that is not
+// treated as a hard failure. This is only supported on Linux; other
+// platforms use a no-op implementation in copy_files_other.go.
+func copyOwnership(src string, dest string) error {
+	var info unix.Stat_t
+	err := unix.Lstat(src, &info)
+	if err != nil {
+		return nil
+	}
+
+	_ = unix.Lchown(dest, int(info.Uid), int(info.Gid))
+
+	return nil
+}
@@ -0,0 +1,34 @@
+// copy_files_other.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+//go:build !linux
+
+package main
+
+// copyXattrs is a no-op on platforms other than Linux, which is the only
+// platform fdbkubernetesmonitor's extended-attribute preservation supports.
+func copyXattrs(src string, dest string) error {
+	return nil
+}
+
+// copyOwnership is a no-op on platforms other than Linux, which is the only
+// platform fdbkubernetesmonitor's ownership preservation supports.
+func copyOwnership(src string, dest string) error {
+	return nil
+}
@@ -0,0 +1,97 @@
+// copy_files_test.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-logr/logr"
+)
+
+func TestCopyFilesTreeWithGlobRequiredNotEmpty(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(src, "secret.pem"), []byte("cert-data"), 0644); err != nil {
+		t.Fatalf("failed to write fixture file: %s", err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "readme.txt"), []byte("not a cert"), 0644); err != nil {
+		t.Fatalf("failed to write fixture file: %s", err)
+	}
+
+	requiredNotEmpty := map[string]bool{"*.pem": true}
+	trees := []treeCopy{{src: src, dst: "tls"}}
+
+	err := CopyFiles(logr.Discard(), dst, map[string]string{}, requiredNotEmpty, trees, nil, nil)
+	if err != nil {
+		t.Fatalf("expected CopyFiles to succeed when the glob pattern matches a non-empty file, got: %s", err)
+	}
+
+	copied := filepath.Join(dst, "tls", "secret.pem")
+	if _, err := os.Stat(copied); err != nil {
+		t.Fatalf("expected %s to have been copied: %s", copied, err)
+	}
+}
+
+func TestCopyPathPreservesMode(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	srcFile := filepath.Join(src, "data.txt")
+	if err := os.WriteFile(srcFile, []byte("contents"), 0644); err != nil {
+		t.Fatalf("failed to write fixture file: %s", err)
+	}
+
+	destFile := filepath.Join(dst, "data.txt")
+	if err := copyPath(logr.Discard(), srcFile, destFile); err != nil {
+		t.Fatalf("expected copyPath to succeed, got: %s", err)
+	}
+
+	srcInfo, err := os.Lstat(srcFile)
+	if err != nil {
+		t.Fatalf("failed to stat source file: %s", err)
+	}
+	destInfo, err := os.Lstat(destFile)
+	if err != nil {
+		t.Fatalf("failed to stat copied file: %s", err)
+	}
+	if destInfo.Mode() != srcInfo.Mode() {
+		t.Fatalf("expected copied file to preserve mode %v, got %v", srcInfo.Mode(), destInfo.Mode())
+	}
+}
+
+func TestCopyFilesRequiredNotEmptyErrorsWhenGlobMatchesNothing(t *testing.T) {
+	src := t.TempDir()
+	dst := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(src, "readme.txt"), []byte("not a cert"), 0644); err != nil {
+		t.Fatalf("failed to write fixture file: %s", err)
+	}
+
+	requiredNotEmpty := map[string]bool{"*.pem": true}
+	trees := []treeCopy{{src: src, dst: "tls"}}
+
+	err := CopyFiles(logr.Discard(), dst, map[string]string{}, requiredNotEmpty, trees, nil, nil)
+	if err == nil {
+		t.Fatal("expected CopyFiles to error when the required glob pattern matches no copied file")
+	}
+}
@@ -0,0 +1,216 @@
+// environment.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// environmentFileParseError is returned when an --additional-env-file
+// cannot be parsed. For dotenv-style files it records the line at which
+// parsing failed so the operator can fix the file without guessing; for
+// YAML and JSON files the line is 0.
+type environmentFileParseError struct {
+	path string
+	line int
+	err  error
+}
+
+func (parseError *environmentFileParseError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", parseError.path, parseError.line, parseError.err)
+}
+
+func (parseError *environmentFileParseError) Unwrap() error {
+	return parseError.err
+}
+
+var envLinePattern = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+var interpolationPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// parseEnvironmentFile loads the additional environment variables from path,
+// auto-detecting the file format from its extension. Supported formats are
+// dotenv-style `KEY=value` files (the default), YAML, and JSON. Values may
+// reference other entries in the file, or variables already present in the
+// process environment, using `${VAR}` or `$VAR` syntax.
+func parseEnvironmentFile(path string) (map[string]string, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawValues map[string]string
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		rawValues = make(map[string]string)
+		err = yaml.Unmarshal(contents, &rawValues)
+		if err != nil {
+			return nil, &environmentFileParseError{path: path, line: 0, err: err}
+		}
+	case ".json":
+		rawValues = make(map[string]string)
+		err = json.Unmarshal(contents, &rawValues)
+		if err != nil {
+			return nil, &environmentFileParseError{path: path, line: 0, err: err}
+		}
+	default:
+		rawValues, err = parseDotEnv(path, contents)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resolveEnvironment(rawValues), nil
+}
+
+// parseDotEnv parses a dotenv-style file, supporting `export KEY=value` or
+// plain `KEY=value` lines, `#`-prefixed comments, blank lines, and quoted
+// values with escape sequences.
+func parseDotEnv(path string, contents []byte) (map[string]string, error) {
+	values := make(map[string]string)
+
+	scanner := bufio.NewScanner(strings.NewReader(string(contents)))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		matches := envLinePattern.FindStringSubmatch(line)
+		if matches == nil {
+			return nil, &environmentFileParseError{path: path, line: lineNumber, err: fmt.Errorf("could not parse environment line %q", line)}
+		}
+
+		value, err := unquote(matches[2])
+		if err != nil {
+			return nil, &environmentFileParseError{path: path, line: lineNumber, err: err}
+		}
+
+		values[matches[1]] = value
+	}
+
+	return values, scanner.Err()
+}
+
+// unquote removes a single layer of matching quotes from value, if present,
+// and expands backslash escape sequences within double-quoted values.
+func unquote(value string) (string, error) {
+	if len(value) < 2 {
+		return value, nil
+	}
+
+	quote := value[0]
+	if (quote != '"' && quote != '\'') || value[len(value)-1] != quote {
+		return value, nil
+	}
+
+	inner := value[1 : len(value)-1]
+	if quote == '\'' {
+		return inner, nil
+	}
+
+	return strconv.Unquote(`"` + inner + `"`)
+}
+
+// resolveEnvironment expands `${VAR}` and `$VAR` references in every value
+// of raw against the rest of raw, falling back to the process environment
+// for names that are not defined in the file. Unlike substituting each
+// value in map iteration order, this resolves each entry's dependencies
+// before the entry itself, regardless of the (randomized) order raw is
+// visited in. Every name that takes part in a reference cycle resolves to
+// its own original, unexpanded raw value, and that outcome does not depend
+// on which member of the cycle is visited first.
+func resolveEnvironment(raw map[string]string) map[string]string {
+	resolved := make(map[string]string, len(raw))
+	inProgress := make(map[string]bool, len(raw))
+	var stack []string
+
+	var resolve func(name string) string
+	resolve = func(name string) string {
+		if value, present := resolved[name]; present {
+			return value
+		}
+
+		rawValue, present := raw[name]
+		if !present {
+			return os.Getenv(name)
+		}
+
+		if inProgress[name] {
+			// A cycle closes back on name. Every entry from name to the
+			// top of the stack is part of the cycle; pin all of them to
+			// their own unexpanded raw value so the result is the same no
+			// matter which one of them started the walk.
+			breakCycle(raw, resolved, stack, name)
+			return raw[name]
+		}
+
+		inProgress[name] = true
+		stack = append(stack, name)
+		value := interpolationPattern.ReplaceAllStringFunc(rawValue, func(match string) string {
+			return resolve(strings.Trim(match, "${}"))
+		})
+		stack = stack[:len(stack)-1]
+		inProgress[name] = false
+
+		if _, alreadyResolved := resolved[name]; !alreadyResolved {
+			resolved[name] = value
+		}
+		return resolved[name]
+	}
+
+	for name := range raw {
+		resolve(name)
+	}
+
+	return resolved
+}
+
+// breakCycle marks every name on stack from cycleStart onward as resolved
+// to its own original raw value. It is called as soon as a cycle is
+// detected, before the in-flight calls for those names finish computing a
+// partially-substituted value, so none of them overwrite this outcome.
+func breakCycle(raw map[string]string, resolved map[string]string, stack []string, cycleStart string) {
+	startIndex := -1
+	for i, name := range stack {
+		if name == cycleStart {
+			startIndex = i
+			break
+		}
+	}
+	if startIndex == -1 {
+		return
+	}
+
+	for _, name := range stack[startIndex:] {
+		resolved[name] = raw[name]
+	}
+}
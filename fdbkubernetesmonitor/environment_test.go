@@ -0,0 +1,84 @@
+// environment_test.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import "testing"
+
+func TestResolveEnvironmentFollowsChainsRegardlessOfOrder(t *testing.T) {
+	raw := map[string]string{
+		"C": "5",
+		"A": "${C}",
+		"B": "${A}",
+	}
+
+	for i := 0; i < 50; i++ {
+		resolved := resolveEnvironment(raw)
+		if resolved["B"] != "5" {
+			t.Fatalf("expected B to resolve to 5 through A and C, got %q", resolved["B"])
+		}
+		if resolved["A"] != "5" {
+			t.Fatalf("expected A to resolve to 5, got %q", resolved["A"])
+		}
+	}
+}
+
+func TestResolveEnvironmentFallsBackToProcessEnvironment(t *testing.T) {
+	t.Setenv("FDBMONITOR_TEST_VAR", "from-environment")
+
+	raw := map[string]string{
+		"A": "${FDBMONITOR_TEST_VAR}",
+	}
+
+	resolved := resolveEnvironment(raw)
+	if resolved["A"] != "from-environment" {
+		t.Fatalf("expected A to fall back to the process environment, got %q", resolved["A"])
+	}
+}
+
+func TestResolveEnvironmentBreaksCycles(t *testing.T) {
+	raw := map[string]string{
+		"A": "${B}",
+		"B": "${A}",
+	}
+
+	// Map iteration order is randomized, so run this enough times to visit
+	// the cycle from both A and B as the entry point; the outcome must not
+	// depend on which one that is.
+	for i := 0; i < 50; i++ {
+		resolved := resolveEnvironment(raw)
+		if resolved["A"] != "${B}" {
+			t.Fatalf("expected A's cyclic reference to be left as its own original value, got %q", resolved["A"])
+		}
+		if resolved["B"] != "${A}" {
+			t.Fatalf("expected B's cyclic reference to be left as its own original value, got %q", resolved["B"])
+		}
+	}
+}
+
+func TestResolveEnvironmentBreaksSelfReferenceCycle(t *testing.T) {
+	raw := map[string]string{
+		"A": "${A}",
+	}
+
+	resolved := resolveEnvironment(raw)
+	if resolved["A"] != "${A}" {
+		t.Fatalf("expected a self-referencing value to be left unresolved, got %q", resolved["A"])
+	}
+}
@@ -0,0 +1,122 @@
+// logging.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"io"
+	"os"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// swappableCore is a zapcore.Core that delegates to whatever core was most
+// recently passed to rebuild. This lets the launcher pick up a new log
+// level or encoding from a --config reload without discarding the
+// *zap.Logger that every other package already holds a reference to.
+// A core derived from it through With is bound to the core that is current
+// at the time of that call and does not follow later rebuilds.
+type swappableCore struct {
+	core atomic.Value
+}
+
+// newSwappableCore wraps initial in a swappableCore.
+func newSwappableCore(initial zapcore.Core) *swappableCore {
+	swappable := &swappableCore{}
+	swappable.core.Store(initial)
+	return swappable
+}
+
+// rebuild atomically replaces the core that this swappableCore delegates
+// to.
+func (swappable *swappableCore) rebuild(core zapcore.Core) {
+	swappable.core.Store(core)
+}
+
+func (swappable *swappableCore) current() zapcore.Core {
+	return swappable.core.Load().(zapcore.Core)
+}
+
+func (swappable *swappableCore) Enabled(level zapcore.Level) bool {
+	return swappable.current().Enabled(level)
+}
+
+func (swappable *swappableCore) With(fields []zapcore.Field) zapcore.Core {
+	return swappable.current().With(fields)
+}
+
+func (swappable *swappableCore) Check(entry zapcore.Entry, checkedEntry *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if swappable.Enabled(entry.Level) {
+		return checkedEntry.AddCore(entry, swappable)
+	}
+	return checkedEntry
+}
+
+func (swappable *swappableCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return swappable.current().Write(entry, fields)
+}
+
+func (swappable *swappableCore) Sync() error {
+	return swappable.current().Sync()
+}
+
+// buildLoggerCore builds a zapcore.Core from the current log-rotation,
+// level, and format flags.
+func buildLoggerCore(logPath string) zapcore.Core {
+	var logWriter io.Writer
+
+	if logPath != "" {
+		lumberjackLogger := &lumberjack.Logger{
+			Filename:   logPath,
+			MaxSize:    logRotateMaxSize,
+			MaxAge:     logRotateMaxAge,
+			MaxBackups: logRotateMaxBackups,
+			Compress:   logRotateCompress,
+		}
+		logWriter = io.MultiWriter(os.Stdout, lumberjackLogger)
+	} else {
+		logWriter = os.Stdout
+	}
+
+	var level zapcore.Level
+	err := level.UnmarshalText([]byte(logLevel))
+	if err != nil {
+		level = zapcore.InfoLevel
+	}
+
+	var encoder zapcore.Encoder
+	if logFormat == "console" {
+		encoder = zapcore.NewConsoleEncoder(zap.NewDevelopmentEncoderConfig())
+	} else {
+		encoder = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	}
+
+	return zapcore.NewCore(encoder, zapcore.AddSync(logWriter), level)
+}
+
+// initLogger builds the launcher's logger along with the swappableCore
+// backing it, so that a later config reload can change the log level or
+// format in place.
+func initLogger(logPath string) (*zap.Logger, *swappableCore) {
+	core := newSwappableCore(buildLoggerCore(logPath))
+	return zap.New(core), core
+}
@@ -0,0 +1,61 @@
+// logging_test.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSwappableCoreRebuildChangesEnabledLevel(t *testing.T) {
+	originalLevel, originalFormat := logLevel, logFormat
+	defer func() { logLevel, logFormat = originalLevel, originalFormat }()
+
+	logLevel = "info"
+	logFormat = "json"
+	core := newSwappableCore(buildLoggerCore(""))
+
+	if core.Enabled(zapcore.DebugLevel) {
+		t.Fatal("expected debug logs to be disabled at info level")
+	}
+
+	logLevel = "debug"
+	core.rebuild(buildLoggerCore(""))
+
+	if !core.Enabled(zapcore.DebugLevel) {
+		t.Fatal("expected rebuild to pick up the new, more verbose log level")
+	}
+}
+
+func TestInitLoggerAppliesConfiguredLevel(t *testing.T) {
+	originalLevel := logLevel
+	defer func() { logLevel = originalLevel }()
+
+	logLevel = "error"
+	_, core := initLogger("")
+
+	if core.Enabled(zapcore.WarnLevel) {
+		t.Fatal("expected warn logs to be disabled when logLevel is error")
+	}
+	if !core.Enabled(zapcore.ErrorLevel) {
+		t.Fatal("expected error logs to be enabled when logLevel is error")
+	}
+}
@@ -20,21 +20,16 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"path"
-	"regexp"
 	"strings"
 
 	"github.com/go-logr/logr"
 	"github.com/go-logr/zapr"
 	"github.com/spf13/pflag"
 	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
-	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 var (
@@ -58,6 +53,19 @@ var (
 	copyLibraries           []string
 	processCount            int
 	enablePprof             bool
+	logRotateMaxSize        int
+	logRotateMaxAge         int
+	logRotateMaxBackups     int
+	logRotateCompress       bool
+	logLevel                string
+	logFormat               string
+	accessLogFile           string
+	accessLogMaxSize        int
+	accessLogMaxBackups     int
+	copyTrees               []string
+	copyIncludeGlobs        []string
+	copyExcludeGlobs        []string
+	configFile              string
 )
 
 type executionMode string
@@ -68,25 +76,6 @@ const (
 	executionModeSidecar  executionMode = "sidecar"
 )
 
-func initLogger(logPath string) *zap.Logger {
-	var logWriter io.Writer
-
-	if logPath != "" {
-		lumberjackLogger := &lumberjack.Logger{
-			Filename:   logPath,
-			MaxSize:    100,
-			MaxAge:     7,
-			MaxBackups: 2,
-			Compress:   false,
-		}
-		logWriter = io.MultiWriter(os.Stdout, lumberjackLogger)
-	} else {
-		logWriter = os.Stdout
-	}
-
-	return zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(logWriter), zapcore.InfoLevel))
-}
-
 func main() {
 	pflag.StringVar(&executionModeString, "mode", "launcher", "Execution mode. Valid options are launcher, sidecar, and init")
 	pflag.StringVar(&fdbserverPath, "fdbserver-path", "/usr/bin/fdbserver", "Path to the fdbserver binary")
@@ -107,9 +96,36 @@ func main() {
 	pflag.IntVar(&processCount, "process-count", 1, "The number of processes to start")
 	pflag.BoolVar(&enablePprof, "enable-pprof", false, "Enables /debug/pprof endpoints on the listen address")
 	pflag.StringVar(&listenAddress, "listen-address", ":8081", "An address and port to listen on")
+	pflag.IntVar(&logRotateMaxSize, "log.maxsize", 100, "The maximum size in megabytes of a log file before it gets rotated")
+	pflag.IntVar(&logRotateMaxAge, "log.maxage", 7, "The maximum number of days to retain old rotated log files")
+	pflag.IntVar(&logRotateMaxBackups, "log.maxbackups", 2, "The maximum number of old rotated log files to retain")
+	pflag.BoolVar(&logRotateCompress, "log.compress", false, "Compress rotated log files with gzip")
+	pflag.StringVar(&logLevel, "log-level", "info", "The minimum enabled logging level (debug, info, warn, error)")
+	pflag.StringVar(&logFormat, "log-format", "json", "The encoding to use for log output. Valid options are json and console")
+	pflag.StringVar(&accessLogFile, "access-log-file", "", "Name of a file to record HTTP access logs for the listen address to. If this is blank, no access log will be recorded")
+	pflag.IntVar(&accessLogMaxSize, "access-log-max-size", 100, "The maximum size in megabytes of an access log file before it gets rotated")
+	pflag.IntVar(&accessLogMaxBackups, "access-log-max-backups", 2, "The maximum number of old rotated access log files to retain")
+	pflag.StringArrayVar(&copyTrees, "copy-tree", nil, "A list of directories to copy recursively, in the form src:dst")
+	pflag.StringArrayVar(&copyIncludeGlobs, "copy-include", nil, "A list of glob patterns. When copying a --copy-tree, only files matching one of these patterns will be copied. If empty, all files are included")
+	pflag.StringArrayVar(&copyExcludeGlobs, "copy-exclude", nil, "A list of glob patterns. When copying a --copy-tree, files matching one of these patterns will not be copied")
+	pflag.StringVar(&configFile, "config", "", "A YAML or JSON file supplying the copy, environment, and process-count settings that would otherwise come from flags. In launcher and sidecar modes, this file is watched for changes")
 	pflag.Parse()
 
-	logger := zapr.NewLogger(initLogger(logPath))
+	// The --config file can supply logLevel/logFormat, so it has to be
+	// loaded and applied before the logger is built from those flags.
+	var launcherConfigValue *launcherConfig
+	if configFile != "" {
+		var err error
+		launcherConfigValue, err = loadLauncherConfig(configFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading --config file %s: %s\n", configFile, err)
+			os.Exit(1)
+		}
+		applyLauncherConfig(launcherConfigValue)
+	}
+
+	zapLogger, logCore := initLogger(logPath)
+	logger := zapr.NewLogger(zapLogger)
 
 	copyDetails, requiredCopies, err := getCopyDetails()
 	if err != nil {
@@ -117,6 +133,12 @@ func main() {
 		os.Exit(1)
 	}
 
+	copyTreeDetails, err := getCopyTrees()
+	if err != nil {
+		logger.Error(err, "Error parsing --copy-tree entries")
+		os.Exit(1)
+	}
+
 	versionBytes, err := os.ReadFile(versionFilePath)
 	if err != nil {
 		panic(err)
@@ -131,21 +153,38 @@ func main() {
 			logger.Error(err, "Error loading additional environment")
 			os.Exit(1)
 		}
-		StartMonitor(context.Background(), logger, fmt.Sprintf("%s/%s", inputDir, monitorConfFile), customEnvironment, processCount, listenAddress, enablePprof)
+		if launcherConfigValue != nil {
+			customEnvironment = mergeEnvironments(customEnvironment, launcherConfigValue.AdditionalEnvironment)
+		}
+		var accessLogger *zap.Logger
+		if accessLogFile != "" {
+			accessLogger = newAccessLogger(accessLogFile, accessLogMaxSize, accessLogMaxBackups)
+		}
+
+		var configUpdates chan ConfigUpdate
+		if configFile != "" {
+			configUpdates = make(chan ConfigUpdate)
+			go watchLauncherConfig(logger, configFile, customEnvironment, configUpdates, logCore)
+		}
+
+		StartMonitor(context.Background(), logger, fmt.Sprintf("%s/%s", inputDir, monitorConfFile), customEnvironment, processCount, listenAddress, enablePprof, accessLogger, configUpdates)
 	case executionModeInit:
-		err = CopyFiles(logger, outputDir, copyDetails, requiredCopies)
+		err = CopyFiles(logger, outputDir, copyDetails, requiredCopies, copyTreeDetails, copyIncludeGlobs, copyExcludeGlobs)
 		if err != nil {
 			logger.Error(err, "Error copying files")
 			os.Exit(1)
 		}
 	case executionModeSidecar:
 		if mainContainerVersion != currentContainerVersion {
-			err = CopyFiles(logger, outputDir, copyDetails, requiredCopies)
+			err = CopyFiles(logger, outputDir, copyDetails, requiredCopies, copyTreeDetails, copyIncludeGlobs, copyExcludeGlobs)
 			if err != nil {
 				logger.Error(err, "Error copying files")
 				os.Exit(1)
 			}
 		}
+		if configFile != "" {
+			go watchLauncherConfig(logger, configFile, nil, nil, logCore)
+		}
 		logger.Info("Waiting for process to be terminated")
 		done := make(chan bool)
 		<-done
@@ -177,6 +216,16 @@ func getCopyDetails() (map[string]string, map[string]bool, error) {
 	}
 	requiredCopyMap := make(map[string]bool, len(requiredCopyFiles))
 	for _, filePath := range requiredCopyFiles {
+		// Glob patterns are meant to match files under a --copy-tree, which
+		// aren't known until CopyFiles walks the tree, so they can't be
+		// validated against the flat copyDetails map here. Pass them
+		// through as-is; CopyFiles enforces that every pattern matches at
+		// least one copied, non-empty file.
+		if isGlobPattern(filePath) {
+			requiredCopyMap[filePath] = true
+			continue
+		}
+
 		fullFilePath := path.Join(inputDir, filePath)
 		_, present := copyDetails[fullFilePath]
 		if !present {
@@ -188,28 +237,33 @@ func getCopyDetails() (map[string]string, map[string]bool, error) {
 	return copyDetails, requiredCopyMap, nil
 }
 
-func loadAdditionalEnvironment(logger logr.Logger) (map[string]string, error) {
-	var customEnvironment = make(map[string]string)
-	if additionalEnvFile != "" {
-		environmentPattern := regexp.MustCompile(`export ([A-Za-z0-9_]+)=([^\n]*)`)
+// isGlobPattern returns true if filePath contains glob metacharacters, and
+// so should be matched against copied files rather than looked up as a
+// literal path.
+func isGlobPattern(filePath string) bool {
+	return strings.ContainsAny(filePath, "*?[")
+}
 
-		file, err := os.Open(additionalEnvFile)
-		if err != nil {
-			return nil, err
-		}
-		defer file.Close()
-
-		envScanner := bufio.NewScanner(file)
-		for envScanner.Scan() {
-			envLine := envScanner.Text()
-			matches := environmentPattern.FindStringSubmatch(envLine)
-			if matches == nil || envLine == "" {
-				logger.Error(nil, "Environment file contains line that we cannot parse", "line", envLine, "environmentPattern", environmentPattern)
-				continue
-			}
-			customEnvironment[matches[1]] = matches[2]
+// getCopyTrees parses the --copy-tree entries, which take the form
+// `src:dst`, into the source/destination pairs CopyFiles uses to walk and
+// copy whole directory trees.
+func getCopyTrees() ([]treeCopy, error) {
+	trees := make([]treeCopy, 0, len(copyTrees))
+	for _, entry := range copyTrees {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid --copy-tree entry %q, expected the form src:dst", entry)
 		}
+		trees = append(trees, treeCopy{src: parts[0], dst: parts[1]})
+	}
+
+	return trees, nil
+}
+
+func loadAdditionalEnvironment(logger logr.Logger) (map[string]string, error) {
+	if additionalEnvFile == "" {
+		return make(map[string]string), nil
 	}
 
-	return customEnvironment, nil
+	return parseEnvironmentFile(additionalEnvFile)
 }
@@ -0,0 +1,57 @@
+// main_test.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import "testing"
+
+func TestGetCopyDetailsAllowsGlobRequiredPatterns(t *testing.T) {
+	originalCopyFiles, originalRequired, originalInputDir := copyFiles, requiredCopyFiles, inputDir
+	defer func() {
+		copyFiles, requiredCopyFiles, inputDir = originalCopyFiles, originalRequired, originalInputDir
+	}()
+
+	inputDir = "."
+	copyFiles = nil
+	requiredCopyFiles = []string{"*.pem"}
+
+	_, requiredCopyMap, err := getCopyDetails()
+	if err != nil {
+		t.Fatalf("expected a glob --require-not-empty pattern to be accepted, got error: %s", err)
+	}
+	if !requiredCopyMap["*.pem"] {
+		t.Fatalf("expected the glob pattern to be carried through to the required-copy map, got %v", requiredCopyMap)
+	}
+}
+
+func TestGetCopyDetailsStillValidatesLiteralRequiredPatterns(t *testing.T) {
+	originalCopyFiles, originalRequired, originalInputDir := copyFiles, requiredCopyFiles, inputDir
+	defer func() {
+		copyFiles, requiredCopyFiles, inputDir = originalCopyFiles, originalRequired, originalInputDir
+	}()
+
+	inputDir = "."
+	copyFiles = nil
+	requiredCopyFiles = []string{"missing-file.txt"}
+
+	_, _, err := getCopyDetails()
+	if err == nil {
+		t.Fatal("expected a literal --require-not-empty pattern with no matching --copy-file entry to error")
+	}
+}
@@ -0,0 +1,321 @@
+// monitor.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"go.uber.org/zap"
+)
+
+// ProcessConfiguration describes a single fdbserver process that the monitor
+// is responsible for starting and supervising.
+type ProcessConfiguration struct {
+	// BinaryPath is the path to the fdbserver binary to run.
+	BinaryPath string `json:"binary_path"`
+
+	// Arguments are the command-line arguments to pass to fdbserver.
+	Arguments []string `json:"arguments"`
+}
+
+// monitorConfig is the on-disk representation of the monitor configuration
+// file referenced by --input-monitor-conf.
+type monitorConfig struct {
+	// RunServers indicates whether the monitor should start fdbserver
+	// processes at all.
+	RunServers *bool `json:"run_servers"`
+
+	// ProcessConfiguration is the template used to build the command line
+	// for each fdbserver process this monitor starts.
+	ProcessConfiguration ProcessConfiguration `json:"process_configuration"`
+}
+
+// Monitor represents the monitor process that is responsible for starting
+// and watching the fdbserver processes.
+type Monitor struct {
+	// Logger is the logger this monitor uses to record events.
+	Logger logr.Logger
+
+	// ConfigFile is the path to the monitor configuration file.
+	ConfigFile string
+
+	// CustomEnvironment holds additional environment variables that are
+	// added to the environment of every process the monitor starts. Process
+	// arguments are passed to the binary unchanged.
+	CustomEnvironment map[string]string
+
+	// ProcessCount is the number of fdbserver processes this monitor should
+	// run.
+	ProcessCount int
+
+	// ListenAddress is the address the monitor's HTTP server listens on.
+	ListenAddress string
+
+	// EnablePprof determines whether the /debug/pprof endpoints are
+	// registered on the HTTP server.
+	EnablePprof bool
+
+	// AccessLogger, when non-nil, receives one entry per HTTP request
+	// handled on ListenAddress.
+	AccessLogger *zap.Logger
+
+	mutex           sync.Mutex
+	processes       map[int]*exec.Cmd
+	processConfig   ProcessConfiguration
+	serversDisabled bool
+}
+
+// ConfigUpdate describes a change to the launcher's config file that the
+// monitor should apply without restarting the process.
+type ConfigUpdate struct {
+	// ProcessCount is the number of fdbserver processes the monitor should
+	// be running after this update.
+	ProcessCount int
+
+	// CustomEnvironment replaces the environment variables used for any
+	// process the monitor starts after this update.
+	CustomEnvironment map[string]string
+}
+
+// StartMonitor starts the monitor loop, which starts and watches the
+// configured fdbserver processes, and serves the monitor's HTTP endpoints on
+// listenAddress. If configUpdates is non-nil, the monitor applies every
+// update it receives in place, without restarting its existing processes.
+func StartMonitor(ctx context.Context, logger logr.Logger, configFile string, customEnvironment map[string]string, processCount int, listenAddress string, enablePprof bool, accessLogger *zap.Logger, configUpdates <-chan ConfigUpdate) {
+	monitor := &Monitor{
+		Logger:            logger,
+		ConfigFile:        configFile,
+		CustomEnvironment: customEnvironment,
+		ProcessCount:      processCount,
+		ListenAddress:     listenAddress,
+		EnablePprof:       enablePprof,
+		AccessLogger:      accessLogger,
+		processes:         make(map[int]*exec.Cmd),
+	}
+
+	go monitor.serveHTTP()
+	monitor.run(ctx, configUpdates)
+}
+
+// serveHTTP starts the monitor's HTTP server, which exposes a health check
+// and, optionally, pprof profiling endpoints.
+func (monitor *Monitor) serveHTTP() {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	if monitor.EnablePprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	var handler http.Handler = mux
+	if monitor.AccessLogger != nil {
+		handler = accessLogMiddleware(monitor.AccessLogger, mux)
+	}
+
+	listener, err := net.Listen("tcp", monitor.ListenAddress)
+	if err != nil {
+		monitor.Logger.Error(err, "Error binding to listen address", "listenAddress", monitor.ListenAddress)
+		return
+	}
+
+	err = http.Serve(listener, handler)
+	if err != nil {
+		monitor.Logger.Error(err, "Error serving HTTP listener")
+	}
+}
+
+// run starts the configured number of fdbserver processes, then blocks
+// until the context is cancelled, applying any config updates it receives
+// in the meantime.
+func (monitor *Monitor) run(ctx context.Context, configUpdates <-chan ConfigUpdate) {
+	config, err := monitor.loadConfig()
+	if err != nil {
+		monitor.Logger.Error(err, "Error loading monitor configuration", "configFile", monitor.ConfigFile)
+		return
+	}
+
+	monitor.processConfig = config.ProcessConfiguration
+
+	if config.RunServers != nil && !*config.RunServers {
+		monitor.Logger.Info("Configuration disables running servers")
+		monitor.serversDisabled = true
+	} else {
+		for processNumber := 1; processNumber <= monitor.ProcessCount; processNumber++ {
+			monitor.startProcess(processNumber, config.ProcessConfiguration)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case update, ok := <-configUpdates:
+			if !ok {
+				configUpdates = nil
+				continue
+			}
+			monitor.applyConfigUpdate(update)
+		}
+	}
+}
+
+// applyConfigUpdate brings the monitor's running processes in line with an
+// updated process count and custom environment, starting or stopping
+// fdbserver processes as needed without touching the ones that are
+// unaffected.
+func (monitor *Monitor) applyConfigUpdate(update ConfigUpdate) {
+	monitor.mutex.Lock()
+	monitor.CustomEnvironment = update.CustomEnvironment
+	processConfig := monitor.processConfig
+	previousCount := monitor.ProcessCount
+	monitor.ProcessCount = update.ProcessCount
+	monitor.mutex.Unlock()
+
+	if update.ProcessCount == previousCount || monitor.serversDisabled {
+		monitor.Logger.Info("Applied configuration update", "processCount", update.ProcessCount)
+		return
+	}
+
+	monitor.Logger.Info("Process count changed", "previousCount", previousCount, "newCount", update.ProcessCount)
+
+	if update.ProcessCount > previousCount {
+		for processNumber := previousCount + 1; processNumber <= update.ProcessCount; processNumber++ {
+			monitor.startProcess(processNumber, processConfig)
+		}
+		return
+	}
+
+	for processNumber := previousCount; processNumber > update.ProcessCount; processNumber-- {
+		monitor.stopProcess(processNumber)
+	}
+}
+
+// stopProcess terminates the fdbserver process with the given number, if
+// the monitor is currently running one.
+func (monitor *Monitor) stopProcess(processNumber int) {
+	monitor.mutex.Lock()
+	cmd, present := monitor.processes[processNumber]
+	delete(monitor.processes, processNumber)
+	monitor.mutex.Unlock()
+
+	if !present || cmd.Process == nil {
+		return
+	}
+
+	err := cmd.Process.Kill()
+	if err != nil {
+		monitor.Logger.Error(err, "Error stopping process", "processNumber", processNumber)
+	}
+}
+
+// loadConfig reads and parses the monitor configuration file.
+func (monitor *Monitor) loadConfig() (*monitorConfig, error) {
+	file, err := os.Open(monitor.ConfigFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	config := &monitorConfig{}
+	err = json.NewDecoder(file).Decode(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// startProcess starts a single fdbserver process and streams its output
+// through the monitor's logger.
+func (monitor *Monitor) startProcess(processNumber int, processConfig ProcessConfiguration) {
+	logger := monitor.Logger.WithValues("processNumber", processNumber)
+
+	cmd := exec.Command(processConfig.BinaryPath, processConfig.Arguments...)
+	cmd.Env = buildEnvironment(monitor.CustomEnvironment)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		logger.Error(err, "Error creating stdout pipe for process")
+		return
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		logger.Error(err, "Error creating stderr pipe for process")
+		return
+	}
+
+	err = cmd.Start()
+	if err != nil {
+		logger.Error(err, "Error starting process")
+		return
+	}
+
+	monitor.mutex.Lock()
+	monitor.processes[processNumber] = cmd
+	monitor.mutex.Unlock()
+
+	go streamOutput(logger, "stdout", stdout)
+	go streamOutput(logger, "stderr", stderr)
+
+	go func() {
+		err := cmd.Wait()
+		if err != nil {
+			logger.Error(err, "Process exited with an error")
+		} else {
+			logger.Info("Process exited")
+		}
+	}()
+}
+
+// streamOutput copies lines from a subprocess pipe into the monitor's
+// logger, tagging each line with the stream it came from.
+func streamOutput(logger logr.Logger, stream string, reader io.Reader) {
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		logger.Info(scanner.Text(), "stream", stream)
+	}
+}
+
+// buildEnvironment combines the current process's environment with the
+// custom environment variables loaded from --additional-env-file.
+func buildEnvironment(customEnvironment map[string]string) []string {
+	environment := os.Environ()
+	for key, value := range customEnvironment {
+		environment = append(environment, fmt.Sprintf("%s=%s", key, value))
+	}
+	return environment
+}
@@ -0,0 +1,120 @@
+// monitor_test.go
+//
+// This source file is part of the FoundationDB open source project
+//
+// Copyright 2021 Apple Inc. and the FoundationDB project authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+func sleepProcessConfig() ProcessConfiguration {
+	return ProcessConfiguration{
+		BinaryPath: "/bin/sh",
+		Arguments:  []string{"-c", "sleep 5"},
+	}
+}
+
+func TestApplyConfigUpdateStartsAndStopsProcesses(t *testing.T) {
+	monitor := &Monitor{
+		Logger:        logr.Discard(),
+		processes:     make(map[int]*exec.Cmd),
+		processConfig: sleepProcessConfig(),
+	}
+	defer func() {
+		for processNumber := range monitor.processes {
+			monitor.stopProcess(processNumber)
+		}
+	}()
+
+	monitor.applyConfigUpdate(ConfigUpdate{ProcessCount: 2})
+	if len(monitor.processes) != 2 {
+		t.Fatalf("expected 2 running processes after scaling up, got %d", len(monitor.processes))
+	}
+
+	monitor.applyConfigUpdate(ConfigUpdate{ProcessCount: 1})
+	if len(monitor.processes) != 1 {
+		t.Fatalf("expected 1 running process after scaling down, got %d", len(monitor.processes))
+	}
+	if _, present := monitor.processes[1]; !present {
+		t.Fatal("expected process 1 to remain running after scaling down from 2 to 1")
+	}
+}
+
+func TestApplyConfigUpdateReplacesCustomEnvironment(t *testing.T) {
+	monitor := &Monitor{
+		Logger:    logr.Discard(),
+		processes: make(map[int]*exec.Cmd),
+	}
+
+	monitor.applyConfigUpdate(ConfigUpdate{ProcessCount: 0, CustomEnvironment: map[string]string{"FOO": "bar"}})
+
+	if monitor.CustomEnvironment["FOO"] != "bar" {
+		t.Fatalf("expected CustomEnvironment to be updated, got %v", monitor.CustomEnvironment)
+	}
+}
+
+func TestRunWithServersDisabledStillDrainsConfigUpdates(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "monitor.json")
+	runServers := false
+	contents, err := json.Marshal(monitorConfig{RunServers: &runServers, ProcessConfiguration: sleepProcessConfig()})
+	if err != nil {
+		t.Fatalf("failed to marshal fixture config: %s", err)
+	}
+	if err := os.WriteFile(configFile, contents, 0644); err != nil {
+		t.Fatalf("failed to write fixture config: %s", err)
+	}
+
+	monitor := &Monitor{
+		Logger:     logr.Discard(),
+		ConfigFile: configFile,
+		processes:  make(map[int]*exec.Cmd),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	configUpdates := make(chan ConfigUpdate)
+	done := make(chan struct{})
+	go func() {
+		monitor.run(ctx, configUpdates)
+		close(done)
+	}()
+
+	select {
+	case configUpdates <- ConfigUpdate{ProcessCount: 0, CustomEnvironment: map[string]string{"FOO": "bar"}}:
+	case <-time.After(time.Second):
+		t.Fatal("expected run to accept a config update while servers are disabled, but the send blocked")
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected run to return after its context was cancelled")
+	}
+}